@@ -0,0 +1,121 @@
+package bots
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// getUpdatesTimeoutSeconds is how long getUpdates asks Telegram to hold the
+// request open waiting for a new update before responding with an empty
+// result, the "long" in long polling. It's comfortably under RequestTimeout
+// so a poll that actually blocks the full duration still returns in time to
+// persist its offset.
+const getUpdatesTimeoutSeconds = 30
+
+// offsetEntity is the single stored entity tracking the last update ID
+// handed to the bot, so that a cron restart doesn't re-dispatch updates
+// that were already processed.
+type offsetEntity struct {
+	Offset int64 `firestore:"offset"`
+}
+
+// offsetKey is the (singleton) key for the persisted update offset.
+var offsetKey = Key{Kind: "Offset", Name: "Root"}
+
+// HandlerFunc is the signature for a command handler registered with
+// Bot.Handle. ctx carries the request context; update is the inbound
+// Telegram update that triggered the call.
+type HandlerFunc func(ctx context.Context, update *Update) error
+
+// Bot receives inbound Telegram updates, either via getUpdates long polling
+// or a webhook, and dispatches them to handlers registered with Handle.
+type Bot struct {
+	handlers map[string]HandlerFunc
+}
+
+// NewBot creates a Bot with no handlers registered.
+func NewBot() *Bot {
+	return &Bot{handlers: make(map[string]HandlerFunc)}
+}
+
+// Handle registers fn to run for messages whose command is cmd, e.g.
+// Handle("/top", ...) matches a message with text "/top" or "/top@yahnc_bot args".
+func (b *Bot) Handle(cmd string, fn HandlerFunc) {
+	b.handlers[cmd] = fn
+}
+
+// Dispatch runs the handler registered for update's command, if any is
+// registered. It is a no-op for updates that aren't commands this bot knows.
+func (b *Bot) Dispatch(ctx context.Context, update *Update) error {
+	if update.Message == nil {
+		return nil
+	}
+	fn, ok := b.handlers[update.Message.Command()]
+	if !ok {
+		return nil
+	}
+	return fn(ctx, update)
+}
+
+// Poll fetches new updates since the last persisted offset via getUpdates
+// long polling, dispatches each to its handler, and persists the new offset
+// so a later cron run doesn't replay them.
+func (b *Bot) Poll(ctx context.Context) error {
+	var offset offsetEntity
+	if err := storage.Get(ctx, offsetKey, &offset); err != nil && err != ErrNoSuchEntity {
+		return errors.WithStack(err)
+	}
+
+	updates, err := getUpdates(ctx, offset.Offset)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for i := range updates {
+		update := &updates[i]
+		if err := b.Dispatch(ctx, update); err != nil {
+			logger.Errorf(ctx, "got error dispatching update %d: %v", update.UpdateID, err)
+		}
+		offset.Offset = update.UpdateID + 1
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+	if err := storage.Put(ctx, offsetKey, &offset); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ServeHTTP implements http.Handler so a Bot can be registered directly as
+// Telegram's webhook target instead of, or in addition to, long polling.
+func (b *Bot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := RequestContext(r)
+	defer cancel()
+
+	var update Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		logger.Errorf(ctx, "got error decoding webhook update: %v", err)
+		return
+	}
+
+	if err := b.Dispatch(ctx, &update); err != nil {
+		logger.Errorf(ctx, "got error dispatching update %d: %v", update.UpdateID, err)
+	}
+}
+
+// getUpdates calls Telegram's getUpdates method for updates after offset.
+func getUpdates(ctx context.Context, offset int64) ([]Update, error) {
+	var response GetUpdatesResponse
+	if err := doTelegram(ctx, "getUpdates", GetUpdatesRequest{Offset: offset, Timeout: getUpdatesTimeoutSeconds}, &response); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !response.OK {
+		return nil, errors.Errorf("getUpdates returned not-OK response: %#v", response)
+	}
+	return response.Result, nil
+}
@@ -0,0 +1,27 @@
+package bots
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// HTTPClient is the subset of *http.Client this bot uses to call the
+// Telegram and HN APIs. Both urlfetch.Client (App Engine) and a plain
+// *http.Client (Cloud Run) already satisfy it, so neither implementation
+// needs an adapter type.
+type HTTPClient interface {
+	Get(url string) (*http.Response, error)
+	Post(url, contentType string, body io.Reader) (*http.Response, error)
+}
+
+// httpClientFor returns the HTTPClient to use for a request running under
+// ctx, chosen once at startup based on which runtime we're on.
+var httpClientFor = newHTTPClientFor()
+
+func newHTTPClientFor() func(context.Context) HTTPClient {
+	if onCloudRun() {
+		return cloudRunHTTPClientFor
+	}
+	return appengineHTTPClientFor
+}
@@ -0,0 +1,14 @@
+package bots
+
+import (
+	"context"
+
+	"google.golang.org/appengine/urlfetch"
+)
+
+// appengineHTTPClientFor returns the request-scoped client used on classic
+// App Engine, deadlined to DefaultTimeout.
+func appengineHTTPClientFor(ctx context.Context) HTTPClient {
+	withTimeout, _ := context.WithTimeout(ctx, DefaultTimeout)
+	return urlfetch.Client(withTimeout)
+}
@@ -0,0 +1,17 @@
+package bots
+
+import (
+	"context"
+	"net/http"
+)
+
+// cloudRunHTTPClient is shared across requests: unlike urlfetch, which needs
+// a client bound to each request's context, a plain *http.Client is safe for
+// concurrent use. Its Timeout limits each call to DefaultTimeout; the
+// caller's ctx is not used.
+var cloudRunHTTPClient = &http.Client{Timeout: DefaultTimeout}
+
+// cloudRunHTTPClientFor returns the client used on Cloud Run.
+func cloudRunHTTPClientFor(ctx context.Context) HTTPClient {
+	return cloudRunHTTPClient
+}
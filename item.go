@@ -0,0 +1,303 @@
+package bots
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Hot is the sign for a hot item, either because it has high score or it has
+// large number of discussions.
+const Hot = "🔥"
+
+// Item is a struct representing a single piece of content fetched from a
+// Source and stored via Storage. Both Storage implementations persist it by
+// reflecting over its exported fields rather than a custom
+// PropertyLoadSaver. firestoreStorage names each field by its firestore tag
+// (e.g. "tokens"); appengineStorage ignores those tags and names each field
+// by its Go name (e.g. "Tokens"). Source is excluded on both via its "-"
+// tags rather than being saved as a derived, key-only field.
+type Item struct {
+	ID          int64     `json:"id" firestore:"id"`
+	URL         string    `json:"url" firestore:"url"`
+	Title       string    `json:"title" firestore:"title"`
+	Descendants int64     `json:"descendants" firestore:"descendants"`
+	Score       int64     `json:"score" firestore:"score"`
+	MessageID   int64     `json:"-" firestore:"messageId"`
+	LastSave    time.Time `json:"-" firestore:"lastSave"`
+	Type        string    `json:"type" firestore:"type"`
+	Source      string    `json:"-" firestore:"-" datastore:"-"`
+	// Rank is the item's position in its source's last fetch, used to
+	// detect front-page rank changes across poll cycles.
+	Rank int `json:"-" firestore:"rank"`
+	// Tokens is the set of terms Title was tokenized into, indexed so
+	// /search can find this item again. Set by SendMessage and refreshed by
+	// EditMessage; appengineStorage persists it as a repeated property,
+	// firestoreStorage as an array field queryable with "array-contains".
+	Tokens              []string `json:"-" firestore:"tokens"`
+	missingFieldsLoaded bool
+}
+
+// NewFromDatastore creates an Item from storage, keyed under source.
+func NewFromDatastore(ctx context.Context, source string, id int64) (Item, error) {
+	var item Item
+	if err := storage.Get(ctx, GetKey(source, id), &item); err != nil {
+		return item, errors.WithStack(err)
+	}
+	item.Source = source
+	return item, nil
+}
+
+// FillMissingFields is used to fill the missing item data from HN API.
+func (s *Item) FillMissingFields(ctx context.Context) error {
+	resp, err := httpClientFor(ctx).Get(ItemURL(s.ID))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(s)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	s.missingFieldsLoaded = true
+	return nil
+}
+
+// ShouldIgnore is a filter for item, using cfg's per-source thresholds.
+func (s *Item) ShouldIgnore(cfg SourceConfig) bool {
+	return s.Type != "story" ||
+		s.Score < cfg.ScoreThreshold ||
+		s.Descendants < cfg.NumCommentsThreshold ||
+		s.URL == ""
+}
+
+// ToSendMessageRequest will return a new SendMessageRequest object
+func (s *Item) ToSendMessageRequest(cfg SourceConfig) SendMessageRequest {
+	return SendMessageRequest{
+		ChatID:      cfg.ChatID,
+		Text:        fmt.Sprintf("<b>%s</b>  %s", s.Title, s.URL),
+		ParseMode:   "HTML",
+		ReplyMarkup: s.GetReplyMarkup(),
+	}
+}
+
+// ToEditMessageTextRequest will return a new EditMessageTextRequest object
+func (s *Item) ToEditMessageTextRequest(cfg SourceConfig) EditMessageTextRequest {
+	return EditMessageTextRequest{
+		ChatID:      cfg.ChatID,
+		MessageID:   s.MessageID,
+		Text:        fmt.Sprintf("<b>%s</b>  %s", s.Title, s.URL),
+		ParseMode:   "HTML",
+		ReplyMarkup: s.GetReplyMarkup(),
+	}
+}
+
+// GetReplyMarkup will return the markup for the item. The comments button
+// is only rendered for HN-backed sources, since other sources have no
+// discussion page to link to.
+func (s *Item) GetReplyMarkup() InlineKeyboardMarkup {
+	var scoreSuffix, commentSuffix string
+	if s.Score > 100 {
+		scoreSuffix = " " + Hot
+	}
+	if s.Descendants > 100 {
+		commentSuffix = " " + Hot
+	}
+	buttons := []InlineKeyboardButton{
+		{
+			Text: fmt.Sprintf("Score: %d+%s", s.Score, scoreSuffix),
+			URL:  s.URL,
+		},
+	}
+	if strings.HasPrefix(s.Source, "hn-") {
+		buttons = append(buttons, InlineKeyboardButton{
+			Text: fmt.Sprintf("Comments: %d+%s", s.Descendants, commentSuffix),
+			URL:  NewsURL(s.ID),
+		})
+	}
+	return InlineKeyboardMarkup{
+		InlineKeyboard: [][]InlineKeyboardButton{buttons},
+	}
+}
+
+// messageLink returns the public t.me URL for messageID as posted to
+// chatID, handling both the "@username" channels most sources use and the
+// "-100"-prefixed numeric chat ID Telegram assigns a channel with no public
+// username.
+func messageLink(chatID string, messageID int64) string {
+	if strings.HasPrefix(chatID, "@") {
+		return fmt.Sprintf("https://t.me/%s/%d", strings.TrimPrefix(chatID, "@"), messageID)
+	}
+	if strings.HasPrefix(chatID, "-100") {
+		return fmt.Sprintf("https://t.me/c/%s/%d", strings.TrimPrefix(chatID, "-100"), messageID)
+	}
+	return fmt.Sprintf("https://t.me/%s/%d", chatID, messageID)
+}
+
+// ToDeleteMessageRequest returns a DeleteMessageRequest.
+func (s *Item) ToDeleteMessageRequest(cfg SourceConfig) DeleteMessageRequest {
+	return DeleteMessageRequest{
+		ChatID:    cfg.ChatID,
+		MessageID: s.MessageID,
+	}
+}
+
+// errNotifyFailed wraps an error from notifyTransitions so runEditMessage
+// can tell the text edit itself already succeeded and a retry only needs to
+// redo the notify step, not resubmit the edit (which would otherwise risk
+// re-evaluating crossedThresholds against the same prev and duplicate-
+// posting the reply, the same bug fixed once already for the edit step).
+type errNotifyFailed struct {
+	err error
+}
+
+// Error implements the error interface.
+func (e *errNotifyFailed) Error() string { return e.err.Error() }
+
+// Cause lets errors.Cause see through to the underlying error, so
+// retryBackoff's *ErrTelegramRetryable check keeps working unchanged.
+func (e *errNotifyFailed) Cause() error { return e.err }
+
+// EditMessage send a request to edit a message. rank is s's position in the
+// source's most recent fetch; prev is the snapshot last saved to datastore,
+// used to detect score/comment/rank transitions worth a threaded reply on
+// top of the usual text edit. notifyOnly skips the text edit and only
+// (re)sends the threaded reply: runEditMessage sets it on a retry that
+// follows an *errNotifyFailed, since the text edit from the failed attempt
+// already succeeded and redoing it would be redundant at best. The reply
+// itself is only sent once the edit it describes has succeeded, and a
+// failure to send it comes back wrapped in *errNotifyFailed rather than
+// bare, so the caller can tell the two failure points apart.
+func (s *Item) EditMessage(ctx context.Context, cfg SourceConfig, rank int, prev Item, notifyOnly bool) error {
+	if !s.missingFieldsLoaded {
+		if err := s.FillMissingFields(ctx); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if s.ShouldIgnore(cfg) {
+		return errors.WithStack(ErrIgnoredItem)
+	}
+	s.Rank = rank
+	// Every edit rewrites the whole entity, so Tokens and LastSave need
+	// refreshing here too; otherwise the next Put would wipe the search
+	// index this item was given by SendMessage, or leave LastSave stuck at
+	// whatever it was when this item was first sent, making Stale() treat
+	// it as abandoned the moment the TTL first elapses regardless of how
+	// recently it was actually edited.
+	s.Tokens = tokenize(s.Title)
+	s.LastSave = time.Now()
+
+	if !notifyOnly {
+		if err := doTelegram(ctx, "editMessageText", s.ToEditMessageTextRequest(cfg), nil); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if err := s.notifyTransitions(ctx, cfg, prev); err != nil {
+		return &errNotifyFailed{err: err}
+	}
+	return nil
+}
+
+// notifyTransitions sends a threaded reply to s's message summarizing any
+// state transition cfg.Notify cares about between prev and s's current
+// state, e.g. score crossing 100/500. It is a no-op if nothing crossed.
+func (s *Item) notifyTransitions(ctx context.Context, cfg SourceConfig, prev Item) error {
+	var changes []string
+	if cfg.Notify.Score {
+		for _, t := range crossedThresholds(prev.Score, s.Score, 100, 500) {
+			changes = append(changes, fmt.Sprintf("score crossed %d (now %d)", t, s.Score))
+		}
+	}
+	if cfg.Notify.Descendants {
+		for _, t := range crossedThresholds(prev.Descendants, s.Descendants, 50, 100) {
+			changes = append(changes, fmt.Sprintf("comments crossed %d (now %d)", t, s.Descendants))
+		}
+	}
+	if cfg.Notify.Rank && prev.Rank != s.Rank {
+		changes = append(changes, fmt.Sprintf("rank changed from #%d to #%d", prev.Rank+1, s.Rank+1))
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	req := SendMessageRequest{
+		ChatID:           cfg.ChatID,
+		Text:             strings.Join(changes, "\n"),
+		ReplyToMessageID: s.MessageID,
+	}
+	return doTelegram(ctx, "sendMessage", req, nil)
+}
+
+// crossedThresholds returns the thresholds in ts that before/after straddle
+// in the increasing direction, e.g. crossedThresholds(90, 120, 100, 500)
+// returns []int64{100}.
+func crossedThresholds(before, after int64, ts ...int64) []int64 {
+	var crossed []int64
+	for _, t := range ts {
+		if before < t && after >= t {
+			crossed = append(crossed, t)
+		}
+	}
+	return crossed
+}
+
+// InDatastore checks if the item is already in storage.
+func (s *Item) InDatastore(ctx context.Context) bool {
+	logger.Infof(ctx, "calling InDatastore")
+	var existing Item
+	return storage.Get(ctx, GetKey(s.Source, s.ID), &existing) == nil
+}
+
+// SendMessage send a request to send a new message. rank is s's position in
+// the source's most recent fetch, saved so later edits can detect rank
+// changes.
+func (s *Item) SendMessage(ctx context.Context, cfg SourceConfig, rank int) error {
+	if !s.missingFieldsLoaded {
+		if err := s.FillMissingFields(ctx); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if s.ShouldIgnore(cfg) {
+		return ErrIgnoredItem
+	} else if s.InDatastore(ctx) {
+		return errors.WithStack(fmt.Errorf("item already posted: %#v", s))
+	}
+	s.Rank = rank
+
+	var response SendMessageResponse
+	if err := doTelegram(ctx, "sendMessage", s.ToSendMessageRequest(cfg), &response); err != nil {
+		return errors.WithStack(err)
+	}
+	s.MessageID = response.Result.MessageID
+	s.Tokens = tokenize(s.Title)
+	s.LastSave = time.Now()
+	return nil
+}
+
+// DeleteMessage delete a message from telegram Channel and from channel.
+func (s *Item) DeleteMessage(ctx context.Context, cfg SourceConfig) error {
+	var response DeleteMessageResponse
+	if err := doTelegram(ctx, "deleteMessage", s.ToDeleteMessageRequest(cfg), &response); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !response.OK {
+		if !response.ShouldIgnoreError() {
+			return errors.WithStack(fmt.Errorf("%#v", response))
+		}
+		logger.Warningf(ctx, "ignoring %#v", response)
+	}
+
+	if err := storage.Delete(ctx, GetKey(s.Source, s.ID)); err != nil {
+		return errors.WithStack(err)
+	}
+	logger.Infof(ctx, "%d (messageID: %d) deleted", s.ID, s.MessageID)
+	return nil
+}
@@ -0,0 +1,30 @@
+package bots
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCrossedThresholds(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after int64
+		thresholds    []int64
+		want          []int64
+	}{
+		{"crosses one", 90, 120, []int64{100, 500}, []int64{100}},
+		{"crosses both", 90, 600, []int64{100, 500}, []int64{100, 500}},
+		{"crosses none, stays below", 10, 90, []int64{100, 500}, nil},
+		{"crosses none, already above", 200, 300, []int64{100, 500}, nil},
+		{"equal to threshold counts as crossed", 99, 100, []int64{100}, []int64{100}},
+		{"decreasing never crosses", 600, 90, []int64{100, 500}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := crossedThresholds(tt.before, tt.after, tt.thresholds...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("crossedThresholds(%d, %d, %v) = %v, want %v", tt.before, tt.after, tt.thresholds, got, tt.want)
+			}
+		})
+	}
+}
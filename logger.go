@@ -0,0 +1,22 @@
+package bots
+
+import "context"
+
+// Logger reports request-scoped diagnostic messages, the same shape as
+// appengine/log's free functions: Infof/Warningf/Errorf each take a
+// printf-style format and args.
+type Logger interface {
+	Infof(ctx context.Context, format string, args ...interface{})
+	Warningf(ctx context.Context, format string, args ...interface{})
+	Errorf(ctx context.Context, format string, args ...interface{})
+}
+
+// logger is the Logger implementation in effect for this process.
+var logger = newLogger()
+
+func newLogger() Logger {
+	if onCloudRun() {
+		return cloudRunLogger{}
+	}
+	return appengineLogger{}
+}
@@ -0,0 +1,23 @@
+package bots
+
+import (
+	"context"
+
+	"google.golang.org/appengine/log"
+)
+
+// appengineLogger is the Logger implementation used on classic App Engine,
+// backed by appengine/log so messages show up alongside request logs.
+type appengineLogger struct{}
+
+func (appengineLogger) Infof(ctx context.Context, format string, args ...interface{}) {
+	log.Infof(ctx, format, args...)
+}
+
+func (appengineLogger) Warningf(ctx context.Context, format string, args ...interface{}) {
+	log.Warningf(ctx, format, args...)
+}
+
+func (appengineLogger) Errorf(ctx context.Context, format string, args ...interface{}) {
+	log.Errorf(ctx, format, args...)
+}
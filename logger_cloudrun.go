@@ -0,0 +1,24 @@
+package bots
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// cloudRunLogger is the Logger implementation used on Cloud Run, backed by
+// log/slog's default logger, which writes each message as one plain text
+// line with its level to the standard error stream.
+type cloudRunLogger struct{}
+
+func (cloudRunLogger) Infof(ctx context.Context, format string, args ...interface{}) {
+	slog.InfoContext(ctx, fmt.Sprintf(format, args...))
+}
+
+func (cloudRunLogger) Warningf(ctx context.Context, format string, args ...interface{}) {
+	slog.WarnContext(ctx, fmt.Sprintf(format, args...))
+}
+
+func (cloudRunLogger) Errorf(ctx context.Context, format string, args ...interface{}) {
+	slog.ErrorContext(ctx, fmt.Sprintf(format, args...))
+}
@@ -2,76 +2,159 @@ package bots
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/pkg/errors"
-	"google.golang.org/appengine"
-	"google.golang.org/appengine/datastore"
-	"google.golang.org/appengine/delay"
-	"google.golang.org/appengine/log"
-	"google.golang.org/appengine/urlfetch"
 )
 
 // TelegramAPIBase is the API base of telegram API.
 const TelegramAPIBase = `https://api.telegram.org/`
 
-// BatchSize is the number of top stories to fetch from Hacker News.
-const BatchSize = 30
-
 // DefaultTimeout is the default URLFetch timeout.
 const DefaultTimeout = 9 * time.Minute
 
-// DefaultChatID is the default chat ID.
+// DefaultChatID is the chat ID used by sources with no SourceConfig of
+// their own yet.
 const DefaultChatID = `@yahnc`
 
-var editMessageFunc = delay.Func("editMessage", func(ctx context.Context, itemID int64, messageID int64) {
-	story := Story{ID: itemID, MessageID: messageID}
-	err := story.EditMessage(ctx)
+// maxTelegramAttempts bounds how many attempts, the first one included, a
+// send/edit/delete gets after retryable Telegram errors (HTTP 429 or 5xx)
+// before giving up.
+const maxTelegramAttempts = 3
+
+// sources lists the content sources polled by handler. Each drives its own
+// datastore root and can be posted to its own Telegram chat via its
+// SourceConfig.
+var sources = []Source{
+	NewHNTopSource(),
+	NewHNBestSource(),
+	NewHNNewSource(),
+	NewHNAskSource(),
+	NewHNShowSource(),
+}
+
+// retryBackoff reports the backoff a retry of err should be scheduled
+// after, and whether a retry should happen at all. It returns (0, false)
+// for non-retryable errors or once maxTelegramAttempts is reached. Callers
+// pass the backoff to their TaskQueue's own delayed-enqueue support rather
+// than sleeping on it: blocking a live request for up to maxRetryBackoff
+// would tie up a Cloud Run instance (or an App Engine request) for minutes
+// per retry, and risks the task's own dispatch deadline elapsing before the
+// sleep even finishes.
+func retryBackoff(err error, attempt int) (time.Duration, bool) {
+	retryable, ok := errors.Cause(err).(*ErrTelegramRetryable)
+	if !ok || attempt+1 >= maxTelegramAttempts {
+		return 0, false
+	}
+	return retryable.RetryAfter, true
+}
+
+// runEditMessage is the body of the "edit" task: it edits source's item id
+// in place, notifying on any transition cfg.Notify cares about, then saves
+// the result. It's registered as a delay.Func on App Engine and dispatched
+// by tasksHandler on Cloud Run. notifyOnly is passed straight through to
+// Item.EditMessage: it's set on a retry that follows an *errNotifyFailed, so
+// that retry doesn't resubmit a text edit that already succeeded.
+func runEditMessage(ctx context.Context, source string, id int64, rank int, prev Item, notifyOnly bool, attempt int) {
+	cfg, err := LoadSourceConfig(ctx, source)
 	if err != nil {
-		if err != ErrIgnoredItem {
-			log.Errorf(ctx, "got error from EditMessage: %#v", err)
+		logger.Errorf(ctx, "got error loading source config for %s: %v", source, err)
+		return
+	}
+	item := Item{ID: id, MessageID: prev.MessageID, Source: source}
+	if err := item.EditMessage(ctx, cfg, rank, prev, notifyOnly); err != nil {
+		if errors.Cause(err) == ErrIgnoredItem {
+			return
 		}
+		if backoff, retry := retryBackoff(err, attempt); retry {
+			_, onlyNotify := err.(*errNotifyFailed)
+			logger.Warningf(ctx, "retrying EditMessage for item %d in %s (attempt %d, notifyOnly %t) after: %v", id, backoff, attempt+1, onlyNotify, err)
+			if err := taskQueue.EnqueueEdit(ctx, source, id, rank, prev, onlyNotify, attempt+1, backoff); err != nil {
+				logger.Errorf(ctx, "got error re-enqueuing EditMessage: %v", err)
+			}
+			return
+		}
+		logger.Errorf(ctx, "got error from EditMessage: %#v", err)
 		return
 	}
-	key := GetKey(ctx, itemID)
-	if _, err := datastore.Put(ctx, key, &story); err != nil {
-		log.Errorf(ctx, "got error from datastore.Put: %v", err)
+	if err := storage.Put(ctx, GetKey(source, id), &item); err != nil {
+		logger.Errorf(ctx, "got error from storage.Put: %v", err)
 	}
-	log.Infof(ctx, "successfully edit message: item %d, messageID %d", itemID, messageID)
-})
+	logger.Infof(ctx, "successfully edit message: source %s, item %d, messageID %d", source, id, item.MessageID)
+}
 
-var sendMessageFunc = delay.Func("sendMessage", func(ctx context.Context, itemID int64) {
-	story := Story{ID: itemID}
-	err := story.SendMessage(ctx)
+// runSendMessage is the body of the "send" task: it sends source's item id
+// as a new message, then saves the result.
+func runSendMessage(ctx context.Context, source string, id int64, rank int, attempt int) {
+	cfg, err := LoadSourceConfig(ctx, source)
 	if err != nil {
-		if err != ErrIgnoredItem {
-			log.Errorf(ctx, "got error from SendMessage: %#v", err)
+		logger.Errorf(ctx, "got error loading source config for %s: %v", source, err)
+		return
+	}
+	item := Item{ID: id, Source: source}
+	if err := item.SendMessage(ctx, cfg, rank); err != nil {
+		if err == ErrIgnoredItem {
+			return
 		}
+		if backoff, retry := retryBackoff(err, attempt); retry {
+			logger.Warningf(ctx, "retrying SendMessage for item %d in %s (attempt %d) after: %v", id, backoff, attempt+1, err)
+			if err := taskQueue.EnqueueSend(ctx, source, id, rank, attempt+1, backoff); err != nil {
+				logger.Errorf(ctx, "got error re-enqueuing SendMessage: %v", err)
+			}
+			return
+		}
+		logger.Errorf(ctx, "got error from SendMessage: %#v", err)
 		return
 	}
-	key := GetKey(ctx, itemID)
-	if _, err := datastore.Put(ctx, key, &story); err != nil {
-		log.Errorf(ctx, "got error from datastore.Put: %v", err)
+	if err := storage.Put(ctx, GetKey(source, id), &item); err != nil {
+		logger.Errorf(ctx, "got error from storage.Put: %v", err)
 	}
-	log.Infof(ctx, "successfully sent message: item %d", itemID)
-})
+	logger.Infof(ctx, "successfully sent message: source %s, item %d", source, id)
+}
 
-var deleteMessageFunc = delay.Func("deleteMessage", func(ctx context.Context, itemID int64, messageID int64) {
-	story := Story{ID: itemID, MessageID: messageID}
-	if err := story.DeleteMessage(ctx); err != nil {
-		log.Errorf(ctx, "got error from DeleteMessage: %v", err)
+// runDeleteMessage is the body of the "delete" task: it deletes source's
+// messageID for item id.
+func runDeleteMessage(ctx context.Context, source string, id int64, messageID int64, attempt int) {
+	cfg, err := LoadSourceConfig(ctx, source)
+	if err != nil {
+		logger.Errorf(ctx, "got error loading source config for %s: %v", source, err)
+		return
+	}
+	item := Item{ID: id, MessageID: messageID, Source: source}
+	if err := item.DeleteMessage(ctx, cfg); err != nil {
+		if backoff, retry := retryBackoff(err, attempt); retry {
+			logger.Warningf(ctx, "retrying DeleteMessage for item %d in %s (attempt %d) after: %v", id, backoff, attempt+1, err)
+			if err := taskQueue.EnqueueDelete(ctx, source, id, messageID, attempt+1, backoff); err != nil {
+				logger.Errorf(ctx, "got error re-enqueuing DeleteMessage: %v", err)
+			}
+			return
+		}
+		logger.Errorf(ctx, "got error from DeleteMessage: %v", err)
 	}
-})
+}
+
+var defaultBot = NewBot()
 
 func init() {
+	defaultBot.Handle("/top", topHandler)
+	defaultBot.Handle("/search", searchHandler)
+
+	if feedURL := os.Getenv("RSS_FEED_URL"); feedURL != "" {
+		sources = append(sources, NewRSSSource("rss", feedURL))
+	}
+
 	http.HandleFunc("/poll", handler)
 	http.HandleFunc("/cleanup", cleanUpHandler)
+	http.HandleFunc("/webhook", defaultBot.ServeHTTP)
+	http.HandleFunc("/getUpdates", pollUpdatesHandler)
+	http.HandleFunc("/tasks", tasksHandler)
+	http.HandleFunc("/search", searchHTTPHandler)
 }
 
 // TelegramAPI is a helper function to get the Telegram API endpoint.
@@ -79,127 +162,145 @@ func TelegramAPI(method string) string {
 	return TelegramAPIBase + os.Getenv("BOT_KEY") + "/" + method
 }
 
-// NewsURL is a helper function to get the URL to the story's HackerNews page.
+// NewsURL is a helper function to get the URL to the item's HackerNews page.
 func NewsURL(id int64) string {
 	return `https://news.ycombinator.com/item?id=` + strconv.FormatInt(id, 10)
 }
 
-// ItemURL is a helper function to get the API of an item.
+// ItemURL is a helper function to get the HN API URL of an item.
 func ItemURL(id int64) string {
 	return fmt.Sprintf(`https://hacker-news.firebaseio.com/v0/item/%d.json`, id)
 }
 
-// GetTopStoryURL is a helper function to get the
-func GetTopStoryURL() string {
-	return fmt.Sprintf(`https://hacker-news.firebaseio.com/v0/topstories.json?orderBy="$key"&limitToFirst=%d`, BatchSize)
-}
-
-// GetKey get a datastore key for the given item ID.
-func GetKey(ctx context.Context, i int64) *datastore.Key {
-	root := datastore.NewKey(ctx, "TopStory", "Root", 0, nil)
-	return datastore.NewKey(ctx, "Story", "", i, root)
+// GetKey returns the storage Key for the given source and item ID. The root
+// kind is the source's name, so each source gets its own entity group and
+// several sources can share one deployment without key collisions.
+func GetKey(source string, i int64) Key {
+	return Key{Kind: "Story", ID: i, Parent: &Key{Kind: source, Name: "Root"}}
 }
 
 func handler(w http.ResponseWriter, r *http.Request) {
-	ctx := appengine.NewContext(r)
+	ctx, cancel := RequestContext(r)
+	defer cancel()
 
-	topStories, err := getTopStories(ctx, BatchSize)
-	if err != nil {
-		log.Errorf(ctx, "error trying to fetch top stories: %v", err)
-		return
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, source := range sources {
+		wg.Add(1)
+		go func(source Source) {
+			defer wg.Done()
+			if err := processSource(ctx, source); err != nil {
+				logger.Errorf(ctx, "error processing source %s: %v", source.Name(), err)
+			}
+		}(source)
 	}
+}
 
-	var keys []*datastore.Key
+// processSource fetches source's current items, diffs them against
+// storage, and enqueues an edit or a send for each.
+func processSource(ctx context.Context, source Source) error {
+	items, err := source.Fetch(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error trying to fetch source "+source.Name())
+	}
 
-	for _, story := range topStories {
-		keys = append(keys, GetKey(ctx, story))
+	keys := make([]Key, len(items))
+	for i, item := range items {
+		keys[i] = GetKey(source.Name(), item.ID)
 	}
 
-	savedStories := make([]Story, BatchSize, BatchSize)
+	savedItems := make([]Item, len(keys))
+	errs := storage.GetMulti(ctx, keys, savedItems)
 
-	err = datastore.GetMulti(ctx, keys, savedStories)
 	var wg sync.WaitGroup
 	defer wg.Wait()
-	if err == nil {
-		log.Infof(ctx, "no unknown news")
-		wg.Add(len(keys))
-		for i, key := range keys {
-			go func(id, messageID int64) {
-				defer wg.Done()
-				editMessageFunc.Call(ctx, id, messageID)
-			}(key.IntID(), savedStories[i].MessageID)
-		}
-		return
-	}
-
-	multiErr, ok := err.(appengine.MultiError)
-
-	if !ok {
-		log.Debugf(ctx, "got unknown error from GetMulti: %#v", err)
-		return
-	}
-
-	for i, err := range multiErr {
-		switch {
-		case err == nil:
+	for i := range keys {
+		switch errs[i] {
+		case nil:
 			wg.Add(1)
-			go func(id, messageID int64) {
+			go func(id int64, rank int, prev Item) {
 				defer wg.Done()
-				editMessageFunc.Call(ctx, id, messageID)
-			}(keys[i].IntID(), savedStories[i].MessageID)
-		case err == datastore.ErrNoSuchEntity:
+				if err := taskQueue.EnqueueEdit(ctx, source.Name(), id, rank, prev, false, 0, 0); err != nil {
+					logger.Errorf(ctx, "got error enqueuing EditMessage: %v", err)
+				}
+			}(items[i].ID, i, savedItems[i])
+		case ErrNoSuchEntity:
 			wg.Add(1)
-			go func(id int64) {
+			go func(id int64, rank int) {
 				defer wg.Done()
-				sendMessageFunc.Call(ctx, id)
-			}(keys[i].IntID())
+				if err := taskQueue.EnqueueSend(ctx, source.Name(), id, rank, 0, 0); err != nil {
+					logger.Errorf(ctx, "got error enqueuing SendMessage: %v", err)
+				}
+			}(items[i].ID, i)
 		default:
-			log.Errorf(ctx, "got unknown error in datastore.MultiGet: %#v", err)
+			logger.Errorf(ctx, "got unknown error in storage.GetMulti: %#v", errs[i])
 		}
 	}
+	return nil
 }
 
-func getTopStories(ctx context.Context, limit int) ([]int64, error) {
-	resp, err := myHTTPClient(ctx).Get(GetTopStoryURL())
+func cleanUpHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := RequestContext(r)
+	defer cancel()
+
+	keys, items, err := storage.Stale(ctx, 24*time.Hour)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed in getTopStories")
+		logger.Errorf(ctx, "error in storage.Stale: %v", err)
+		return
 	}
-	defer resp.Body.Close()
 
-	var ret []int64
-	if err := json.NewDecoder(resp.Body).Decode(&ret); err != nil {
-		return nil, errors.Wrap(err, "failed in getTopStories")
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for i, item := range items {
+		var source string
+		if keys[i].Parent != nil {
+			source = keys[i].Parent.Kind
+		}
+		wg.Add(1)
+		go func(source string, id, messageID int64) {
+			defer wg.Done()
+			if err := taskQueue.EnqueueDelete(ctx, source, id, messageID, 0, 0); err != nil {
+				logger.Errorf(ctx, "got error enqueuing DeleteMessage: %v", err)
+			}
+		}(source, item.ID, item.MessageID)
 	}
-
-	return ret, nil
 }
 
-func myHTTPClient(ctx context.Context) *http.Client {
-	withTimeout, _ := context.WithTimeout(ctx, DefaultTimeout)
-	return urlfetch.Client(withTimeout)
+// pollUpdatesHandler is a cron target that drives defaultBot.Poll, used
+// instead of (or alongside) the /webhook handler when getUpdates long
+// polling is preferred over Telegram pushing updates to us.
+func pollUpdatesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := RequestContext(r)
+	defer cancel()
+	if err := defaultBot.Poll(ctx); err != nil {
+		logger.Errorf(ctx, "error trying to poll updates: %v", err)
+	}
 }
 
-func cleanUpHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := appengine.NewContext(r)
-	var allStories []Story
-	_, err := datastore.NewQuery("Story").GetAll(ctx, &allStories)
+// topHandler implements the /top command: it replies in the requesting
+// chat with the current HN top stories.
+func topHandler(ctx context.Context, update *Update) error {
+	items, err := NewHNTopSource().Fetch(ctx)
 	if err != nil {
-		log.Errorf(ctx, "error in GetAll(): %v", err)
-		return
+		return errors.WithStack(err)
+	}
+	if len(items) > 5 {
+		items = items[:5]
 	}
 
-	var wg sync.WaitGroup
-	defer wg.Wait()
-
-	now := time.Now()
-
-	for _, story := range allStories {
-		if now.Sub(story.LastSave) > 24*time.Hour {
-			wg.Add(1)
-			go func(id, messageID int64) {
-				defer wg.Done()
-				deleteMessageFunc.Call(ctx, id, messageID)
-			}(story.ID, story.MessageID)
+	var lines []string
+	for i := range items {
+		item := &items[i]
+		if err := item.FillMissingFields(ctx); err != nil {
+			return errors.WithStack(err)
 		}
+		lines = append(lines, fmt.Sprintf("<b>%s</b>  %s", item.Title, item.URL))
+	}
+
+	req := SendMessageRequest{
+		ChatID:    strconv.FormatInt(update.Message.Chat.ID, 10),
+		Text:      strings.Join(lines, "\n"),
+		ParseMode: "HTML",
 	}
+	return doTelegram(ctx, "sendMessage", req, nil)
 }
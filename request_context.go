@@ -0,0 +1,24 @@
+package bots
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/appengine"
+)
+
+// RequestContext returns the context for serving r, deadlined to
+// RequestTimeout so fanned-out goroutines notice the request is being torn
+// down and stop instead of leaking. On App Engine this wraps
+// appengine.NewContext; on Cloud Run, r.Context() already carries Cloud
+// Run's own request deadline, so it's used directly.
+//
+// TODO: drop the appengine.NewContext branch (and its import) once the
+// classic runtime is fully decommissioned.
+func RequestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	base := r.Context()
+	if !onCloudRun() {
+		base = appengine.NewContext(r)
+	}
+	return context.WithTimeout(base, RequestTimeout)
+}
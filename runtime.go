@@ -0,0 +1,12 @@
+package bots
+
+import "os"
+
+// onCloudRun reports whether the process is running as a Cloud Run or Cloud
+// Functions Gen2 service rather than the legacy App Engine first-generation
+// runtime. Cloud Run and Functions Gen2 always set K_SERVICE; classic App
+// Engine never does, so this is enough to pick the right Storage, TaskQueue,
+// Logger and HTTPClient implementation at startup.
+func onCloudRun() bool {
+	return os.Getenv("K_SERVICE") != ""
+}
@@ -0,0 +1,136 @@
+package bots
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// stopWords are common English words excluded from the search index since
+// they match too many stories to be useful as a search term.
+var stopWords = map[string]struct{}{
+	"a": {}, "an": {}, "and": {}, "are": {}, "as": {}, "at": {}, "be": {},
+	"by": {}, "for": {}, "from": {}, "how": {}, "in": {}, "is": {}, "it": {},
+	"of": {}, "on": {}, "or": {}, "that": {}, "the": {}, "to": {}, "was": {},
+	"what": {}, "when": {}, "with": {},
+}
+
+// tokenRe matches runs of ASCII letters and digits, used to split a title
+// into words while stripping punctuation; any other character, non-ASCII
+// letters included, splits words and is dropped.
+var tokenRe = regexp.MustCompile(`[a-z0-9]+`)
+
+// tokenize turns title into the deduplicated, lowercased, stopword-free set
+// of terms Item.Tokens is indexed under, preserving first-occurrence order.
+func tokenize(title string) []string {
+	seen := make(map[string]struct{})
+	var tokens []string
+	for _, word := range tokenRe.FindAllString(strings.ToLower(title), -1) {
+		if _, ignore := stopWords[word]; ignore {
+			continue
+		}
+		if _, dup := seen[word]; dup {
+			continue
+		}
+		seen[word] = struct{}{}
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
+
+// containsAll reports whether have contains every token in want.
+func containsAll(have, want []string) bool {
+	set := make(map[string]struct{}, len(have))
+	for _, t := range have {
+		set[t] = struct{}{}
+	}
+	for _, t := range want {
+		if _, ok := set[t]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// searchHandler implements the /search command: it replies in the
+// requesting chat with up to 5 previously posted stories matching query.
+func searchHandler(ctx context.Context, update *Update) error {
+	tokens := tokenize(update.Message.Args())
+	if len(tokens) == 0 {
+		return doTelegram(ctx, "sendMessage", SendMessageRequest{
+			ChatID: strconv.FormatInt(update.Message.Chat.ID, 10),
+			Text:   "usage: /search <query>",
+		}, nil)
+	}
+
+	items, err := storage.Search(ctx, tokens)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(items) > 5 {
+		items = items[:5]
+	}
+
+	req := SendMessageRequest{
+		ChatID: strconv.FormatInt(update.Message.Chat.ID, 10),
+		Text:   fmt.Sprintf("%d result(s) for %q", len(items), update.Message.Args()),
+	}
+	if len(items) > 0 {
+		req.ReplyMarkup = searchResultsMarkup(ctx, items)
+	}
+	return doTelegram(ctx, "sendMessage", req, nil)
+}
+
+// searchResultsMarkup renders items as one button per row, each linking to
+// the channel message that announced it. Sources can post to their own
+// ChatID rather than always @yahnc, so the link is built from
+// each item's own source's SourceConfig.
+func searchResultsMarkup(ctx context.Context, items []Item) InlineKeyboardMarkup {
+	cfgs := make(map[string]SourceConfig, len(items))
+	var rows [][]InlineKeyboardButton
+	for _, item := range items {
+		cfg, ok := cfgs[item.Source]
+		if !ok {
+			var err error
+			cfg, err = LoadSourceConfig(ctx, item.Source)
+			if err != nil {
+				logger.Errorf(ctx, "error loading source config for %s: %v", item.Source, err)
+				cfg = defaultSourceConfig
+			}
+			cfgs[item.Source] = cfg
+		}
+		rows = append(rows, []InlineKeyboardButton{
+			{
+				Text: item.Title,
+				URL:  messageLink(cfg.ChatID, item.MessageID),
+			},
+		})
+	}
+	return InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// searchHTTPHandler implements the standalone GET /search?q=... endpoint,
+// returning matching stories as JSON instead of a Telegram message.
+func searchHTTPHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := RequestContext(r)
+	defer cancel()
+
+	tokens := tokenize(r.URL.Query().Get("q"))
+	items, err := storage.Search(ctx, tokens)
+	if err != nil {
+		logger.Errorf(ctx, "error in storage.Search: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(items); err != nil {
+		logger.Errorf(ctx, "error encoding search results: %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package bots
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  []string
+	}{
+		{
+			name:  "strips punctuation and lowercases",
+			title: "Show HN: Foo-Bar, a New Tool!",
+			want:  []string{"show", "hn", "foo", "bar", "new", "tool"},
+		},
+		{
+			name:  "drops stop words",
+			title: "The Best of What Is and Was",
+			want:  []string{"best"},
+		},
+		{
+			name:  "dedupes preserving first occurrence order",
+			title: "go go gopher",
+			want:  []string{"go", "gopher"},
+		},
+		{
+			name:  "keeps digits",
+			title: "Version 2.0 released",
+			want:  []string{"version", "2", "0", "released"},
+		},
+		{
+			name:  "empty title",
+			title: "",
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenize(tt.title); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenize(%q) = %#v, want %#v", tt.title, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package bots
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Source fetches candidate Items for a single content feed, e.g. HN top
+// stories or an RSS feed. Each Source drives its own cron pass and datastore
+// root, so several of them can share one deployment and post to different
+// Telegram chats.
+type Source interface {
+	// Name uniquely identifies the source. It doubles as the datastore root
+	// kind for the source's items and the key of its SourceConfig.
+	Name() string
+	// Fetch returns the current candidate items for this source, tagged
+	// with its Name.
+	Fetch(ctx context.Context) ([]Item, error)
+}
+
+// SourceConfig holds the per-source settings that used to be package
+// constants (ScoreThreshold, NumCommentsThreshold, BatchSize, DefaultChatID).
+type SourceConfig struct {
+	ScoreThreshold       int64        `firestore:"scoreThreshold"`
+	NumCommentsThreshold int64        `firestore:"numCommentsThreshold"`
+	BatchSize            int          `firestore:"batchSize"`
+	ChatID               string       `firestore:"chatId"`
+	Notify               NotifyPolicy `firestore:"notify"`
+}
+
+// NotifyPolicy controls which score/comment-count/rank transitions get a
+// threaded reply summarizing the change, on top of the usual message edit.
+type NotifyPolicy struct {
+	// Score fires a reply when Score crosses 100 or 500.
+	Score bool `firestore:"score"`
+	// Descendants fires a reply when Descendants crosses 50 or 100.
+	Descendants bool `firestore:"descendants"`
+	// Rank fires a reply when an item's front-page rank changes. Off by
+	// default: a 30-item top list churns ranks on almost every poll, which
+	// would make this reply noise rather than signal.
+	Rank bool `firestore:"rank"`
+}
+
+// defaultSourceConfig is used for a source with no SourceConfig entity in
+// datastore yet, matching the thresholds this bot used to hard-code.
+var defaultSourceConfig = SourceConfig{
+	ScoreThreshold:       100,
+	NumCommentsThreshold: 10,
+	BatchSize:            30,
+	ChatID:               DefaultChatID,
+	Notify: NotifyPolicy{
+		Score:       true,
+		Descendants: true,
+	},
+}
+
+// sourceConfigKey returns the storage key for name's config entity.
+func sourceConfigKey(name string) Key {
+	return Key{Kind: "SourceConfig", Name: name}
+}
+
+// sourceDefaults holds the SourceConfig fallback to use for a source name
+// with no SourceConfig entity yet, for sources whose defaults shouldn't be
+// defaultSourceConfig's HN-shaped thresholds. A Source registers its
+// fallback via registerSourceDefault when constructed.
+var sourceDefaults = map[string]SourceConfig{}
+
+// registerSourceDefault sets the SourceConfig LoadSourceConfig falls back to
+// for name until an operator saves one of their own via SaveSourceConfig.
+func registerSourceDefault(name string, cfg SourceConfig) {
+	sourceDefaults[name] = cfg
+}
+
+// LoadSourceConfig loads name's config from storage, falling back to
+// whatever default name registered via registerSourceDefault, or
+// defaultSourceConfig if it didn't register one.
+func LoadSourceConfig(ctx context.Context, name string) (SourceConfig, error) {
+	var cfg SourceConfig
+	err := storage.Get(ctx, sourceConfigKey(name), &cfg)
+	if err == ErrNoSuchEntity {
+		if def, ok := sourceDefaults[name]; ok {
+			return def, nil
+		}
+		return defaultSourceConfig, nil
+	}
+	if err != nil {
+		return cfg, errors.WithStack(err)
+	}
+	return cfg, nil
+}
+
+// SaveSourceConfig persists cfg as name's config.
+func SaveSourceConfig(ctx context.Context, name string, cfg SourceConfig) error {
+	return errors.WithStack(storage.Put(ctx, sourceConfigKey(name), &cfg))
+}
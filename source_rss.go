@@ -0,0 +1,87 @@
+package bots
+
+import (
+	"context"
+	"encoding/xml"
+	"hash/fnv"
+
+	"github.com/pkg/errors"
+)
+
+// rssFeed is the minimal subset of RSS 2.0/Atom fields we need to extract
+// items from a feed.
+type rssFeed struct {
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Entries []rssItem `xml:"entry"`
+}
+
+// rssItem is the minimal subset of RSS <item>/Atom <entry> fields we read.
+type rssItem struct {
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+// rssSource fetches a generic RSS or Atom feed and turns each entry into an
+// Item tagged with this source's name. RSS entries have no HN-style score
+// or comment count, so NewRSSSource registers a zero-threshold
+// SourceConfig default for name, overriding the HN-shaped
+// defaultSourceConfig that would otherwise make ShouldIgnore filter out
+// every entry until an operator saves a SourceConfig of their own.
+type rssSource struct {
+	name    string
+	feedURL string
+}
+
+// NewRSSSource returns a Source over an arbitrary RSS/Atom feed.
+func NewRSSSource(name, feedURL string) Source {
+	registerSourceDefault(name, SourceConfig{ChatID: DefaultChatID})
+	return &rssSource{name: name, feedURL: feedURL}
+}
+
+// Name implements Source.
+func (r *rssSource) Name() string { return r.name }
+
+// Fetch implements Source.
+func (r *rssSource) Fetch(ctx context.Context) ([]Item, error) {
+	resp, err := httpClientFor(ctx).Get(r.feedURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed fetching "+r.name)
+	}
+	defer resp.Body.Close()
+
+	var feed rssFeed
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return nil, errors.Wrap(err, "failed decoding "+r.name)
+	}
+
+	entries := feed.Channel.Items
+	if len(entries) == 0 {
+		entries = feed.Entries
+	}
+
+	items := make([]Item, 0, len(entries))
+	for _, e := range entries {
+		if e.Link == "" {
+			continue
+		}
+		items = append(items, Item{
+			ID:                  rssItemID(e.Link),
+			Source:              r.name,
+			Title:               e.Title,
+			URL:                 e.Link,
+			Type:                "story",
+			missingFieldsLoaded: true,
+		})
+	}
+	return items, nil
+}
+
+// rssItemID derives a stable int64 ID for an RSS entry from its link, since
+// unlike HN items RSS entries have no numeric ID of their own.
+func rssItemID(link string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(link))
+	return int64(h.Sum64())
+}
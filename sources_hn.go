@@ -0,0 +1,113 @@
+package bots
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// hnListURL is one of HN's Firebase list endpoints, e.g. listKey
+// "topstories", "beststories", "newstories", "askstories", "showstories".
+func hnListURL(listKey string, limit int) string {
+	return fmt.Sprintf(`https://hacker-news.firebaseio.com/v0/%s.json?orderBy="$key"&limitToFirst=%d`, listKey, limit)
+}
+
+// hnIDs fetches the item IDs for name/listKey, using cfg.BatchSize as the
+// fetch limit.
+func hnIDs(ctx context.Context, name, listKey string) ([]int64, error) {
+	cfg, err := LoadSourceConfig(ctx, name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	resp, err := httpClientFor(ctx).Get(hnListURL(listKey, cfg.BatchSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed fetching "+name)
+	}
+	defer resp.Body.Close()
+
+	var ids []int64
+	if err := json.NewDecoder(resp.Body).Decode(&ids); err != nil {
+		return nil, errors.Wrap(err, "failed decoding "+name)
+	}
+	return ids, nil
+}
+
+// hnSource fetches item IDs from one of HN's Firebase list endpoints and
+// turns them into Items tagged with this source's name.
+type hnSource struct {
+	name    string
+	listKey string
+}
+
+// NewHNTopSource returns a Source over HN's front-page top stories.
+func NewHNTopSource() Source { return &hnSource{name: "hn-top", listKey: "topstories"} }
+
+// NewHNBestSource returns a Source over HN's best-ranked stories.
+func NewHNBestSource() Source { return &hnSource{name: "hn-best", listKey: "beststories"} }
+
+// NewHNNewSource returns a Source over HN's newest submissions.
+func NewHNNewSource() Source { return &hnSource{name: "hn-new", listKey: "newstories"} }
+
+// Name implements Source.
+func (h *hnSource) Name() string { return h.name }
+
+// Fetch implements Source.
+func (h *hnSource) Fetch(ctx context.Context) ([]Item, error) {
+	ids, err := hnIDs(ctx, h.name, h.listKey)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, len(ids))
+	for i, id := range ids {
+		items[i] = Item{ID: id, Source: h.name}
+	}
+	return items, nil
+}
+
+// hnAskShowSource fetches HN's Ask/Show HN lists and filters out entries
+// whose title doesn't actually start with the expected prefix, since HN's
+// askstories/showstories endpoints occasionally include unrelated posts.
+type hnAskShowSource struct {
+	name    string
+	listKey string
+	prefix  string
+}
+
+// NewHNAskSource returns a Source over HN's "Ask HN" submissions.
+func NewHNAskSource() Source {
+	return &hnAskShowSource{name: "hn-ask", listKey: "askstories", prefix: "Ask HN:"}
+}
+
+// NewHNShowSource returns a Source over HN's "Show HN" submissions.
+func NewHNShowSource() Source {
+	return &hnAskShowSource{name: "hn-show", listKey: "showstories", prefix: "Show HN:"}
+}
+
+// Name implements Source.
+func (h *hnAskShowSource) Name() string { return h.name }
+
+// Fetch implements Source.
+func (h *hnAskShowSource) Fetch(ctx context.Context) ([]Item, error) {
+	ids, err := hnIDs(ctx, h.name, h.listKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	for _, id := range ids {
+		item := Item{ID: id, Source: h.name}
+		if err := item.FillMissingFields(ctx); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if !strings.HasPrefix(item.Title, h.prefix) {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,70 @@
+package bots
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ErrNoSuchEntity is returned by Storage.Get and reported per-key by
+// Storage.GetMulti when no entity exists for a key, regardless of which
+// Storage implementation is in use.
+var ErrNoSuchEntity = errors.New("bots: no such entity")
+
+// Key identifies a stored entity. It generalizes both a datastore ancestor
+// key and a Firestore document path as an optional Parent plus either a
+// Name (string ID) or ID (numeric ID, used when Name is empty).
+type Key struct {
+	Kind   string
+	Name   string
+	ID     int64
+	Parent *Key
+}
+
+// docID returns the string form of whichever of Name/ID identifies k,
+// preferring Name. Used by the Firestore implementation, where every
+// document needs a string ID.
+func (k Key) docID() string {
+	if k.Name != "" {
+		return k.Name
+	}
+	return strconv.FormatInt(k.ID, 10)
+}
+
+// Storage persists Items, SourceConfigs and the bot's update offset. It
+// abstracts over the two places this bot can run: classic App Engine
+// datastore, and Firestore on Cloud Run/Functions Gen2.
+type Storage interface {
+	// Get loads the entity at key into dst, a pointer to an Item,
+	// SourceConfig or offsetEntity. It returns ErrNoSuchEntity if key
+	// doesn't exist.
+	Get(ctx context.Context, key Key, dst interface{}) error
+	// GetMulti loads keys into dst, one entry per key, in order. The
+	// returned slice has one error per key: nil, ErrNoSuchEntity, or some
+	// other error, mirroring datastore.MultiError.
+	GetMulti(ctx context.Context, keys []Key, dst []Item) []error
+	// Put saves src, a pointer to an Item, SourceConfig or offsetEntity, at
+	// key, creating or overwriting it.
+	Put(ctx context.Context, key Key, src interface{}) error
+	// Delete removes the entity at key. It is not an error if key doesn't
+	// exist.
+	Delete(ctx context.Context, key Key) error
+	// Stale returns the keys and Items last saved more than ttl ago, across
+	// every source.
+	Stale(ctx context.Context, ttl time.Duration) ([]Key, []Item, error)
+	// Search returns every Item whose Tokens contains all of tokens, across
+	// every source.
+	Search(ctx context.Context, tokens []string) ([]Item, error)
+}
+
+// storage is the Storage implementation in effect for this process, chosen
+// once at startup based on which runtime we're on.
+var storage = newStorage()
+
+func newStorage() Storage {
+	if onCloudRun() {
+		return &firestoreStorage{}
+	}
+	return &appengineStorage{}
+}
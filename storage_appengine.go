@@ -0,0 +1,130 @@
+package bots
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/datastore"
+)
+
+// appengineStorage is the Storage implementation used on classic App
+// Engine, backed directly by appengine/datastore.
+type appengineStorage struct{}
+
+// toDatastoreKey converts k to the equivalent ancestor *datastore.Key.
+func toDatastoreKey(ctx context.Context, k Key) *datastore.Key {
+	var parent *datastore.Key
+	if k.Parent != nil {
+		parent = toDatastoreKey(ctx, *k.Parent)
+	}
+	return datastore.NewKey(ctx, k.Kind, k.Name, k.ID, parent)
+}
+
+// fromDatastoreKey converts k to our runtime-agnostic Key.
+func fromDatastoreKey(k *datastore.Key) Key {
+	key := Key{Kind: k.Kind(), Name: k.StringID(), ID: k.IntID()}
+	if parent := k.Parent(); parent != nil {
+		p := fromDatastoreKey(parent)
+		key.Parent = &p
+	}
+	return key
+}
+
+func (appengineStorage) Get(ctx context.Context, key Key, dst interface{}) error {
+	err := datastore.Get(ctx, toDatastoreKey(ctx, key), dst)
+	if err == datastore.ErrNoSuchEntity {
+		return ErrNoSuchEntity
+	}
+	return errors.WithStack(err)
+}
+
+func (appengineStorage) GetMulti(ctx context.Context, keys []Key, dst []Item) []error {
+	dsKeys := make([]*datastore.Key, len(keys))
+	for i, k := range keys {
+		dsKeys[i] = toDatastoreKey(ctx, k)
+	}
+
+	err := datastore.GetMulti(ctx, dsKeys, dst)
+	if err == nil {
+		return make([]error, len(keys))
+	}
+
+	multiErr, ok := err.(appengine.MultiError)
+	if !ok {
+		errs := make([]error, len(keys))
+		for i := range errs {
+			errs[i] = errors.WithStack(err)
+		}
+		return errs
+	}
+
+	errs := make([]error, len(multiErr))
+	for i, e := range multiErr {
+		switch e {
+		case nil:
+		case datastore.ErrNoSuchEntity:
+			errs[i] = ErrNoSuchEntity
+		default:
+			errs[i] = errors.WithStack(e)
+		}
+	}
+	return errs
+}
+
+func (appengineStorage) Put(ctx context.Context, key Key, src interface{}) error {
+	_, err := datastore.Put(ctx, toDatastoreKey(ctx, key), src)
+	return errors.WithStack(err)
+}
+
+func (appengineStorage) Delete(ctx context.Context, key Key) error {
+	return errors.WithStack(datastore.Delete(ctx, toDatastoreKey(ctx, key)))
+}
+
+func (appengineStorage) Stale(ctx context.Context, ttl time.Duration) ([]Key, []Item, error) {
+	var allItems []Item
+	dsKeys, err := datastore.NewQuery("Story").GetAll(ctx, &allItems)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	var keys []Key
+	var items []Item
+	cutoff := time.Now().Add(-ttl)
+	for i, item := range allItems {
+		if item.LastSave.After(cutoff) {
+			continue
+		}
+		keys = append(keys, fromDatastoreKey(dsKeys[i]))
+		items = append(items, item)
+	}
+	return keys, items, nil
+}
+
+// Search runs one equality filter per token against the repeated "Tokens"
+// property, which datastore intersects: an entity only matches if every
+// filter is satisfied by some value in its Tokens. This requires a
+// composite index on Tokens for any query using more than one token.
+func (appengineStorage) Search(ctx context.Context, tokens []string) ([]Item, error) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	q := datastore.NewQuery("Story")
+	for _, t := range tokens {
+		q = q.Filter("Tokens =", t)
+	}
+
+	var items []Item
+	keys, err := q.GetAll(ctx, &items)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i, k := range keys {
+		if parent := k.Parent(); parent != nil {
+			items[i].Source = parent.Kind()
+		}
+	}
+	return items, nil
+}
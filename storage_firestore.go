@@ -0,0 +1,212 @@
+package bots
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// firestoreProjectEnv is the project ID Cloud Run sets in the environment,
+// used to create the Firestore client lazily on first use.
+const firestoreProjectEnv = "GOOGLE_CLOUD_PROJECT"
+
+// firestoreStorage is the Storage implementation used on Cloud Run and
+// Functions Gen2. A Key maps onto a document path by nesting one
+// collection per Kind: a root Key becomes collection(Kind).doc(docID), and
+// a Key with a Parent becomes a subcollection one level below the parent's
+// document, mirroring the ancestor keys the appengine implementation uses.
+type firestoreStorage struct {
+	once   sync.Once
+	client *firestore.Client
+	err    error
+}
+
+func (s *firestoreStorage) db(ctx context.Context) (*firestore.Client, error) {
+	s.once.Do(func() {
+		s.client, s.err = firestore.NewClient(ctx, os.Getenv(firestoreProjectEnv))
+	})
+	return s.client, s.err
+}
+
+func (s *firestoreStorage) docRef(ctx context.Context, key Key) (*firestore.DocumentRef, error) {
+	db, err := s.db(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if key.Parent == nil {
+		return db.Collection(key.Kind).Doc(key.docID()), nil
+	}
+	parent, err := s.docRef(ctx, *key.Parent)
+	if err != nil {
+		return nil, err
+	}
+	return parent.Collection(key.Kind).Doc(key.docID()), nil
+}
+
+func (s *firestoreStorage) Get(ctx context.Context, key Key, dst interface{}) error {
+	ref, err := s.docRef(ctx, key)
+	if err != nil {
+		return err
+	}
+	snap, err := ref.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return ErrNoSuchEntity
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(snap.DataTo(dst))
+}
+
+func (s *firestoreStorage) GetMulti(ctx context.Context, keys []Key, dst []Item) []error {
+	errs := make([]error, len(keys))
+
+	refs := make([]*firestore.DocumentRef, len(keys))
+	for i, k := range keys {
+		ref, err := s.docRef(ctx, k)
+		if err != nil {
+			for j := range errs {
+				errs[j] = err
+			}
+			return errs
+		}
+		refs[i] = ref
+	}
+
+	db, err := s.db(ctx)
+	if err != nil {
+		for i := range errs {
+			errs[i] = errors.WithStack(err)
+		}
+		return errs
+	}
+
+	snaps, err := db.GetAll(ctx, refs)
+	if err != nil {
+		for i := range errs {
+			errs[i] = errors.WithStack(err)
+		}
+		return errs
+	}
+
+	for i, snap := range snaps {
+		if !snap.Exists() {
+			errs[i] = ErrNoSuchEntity
+			continue
+		}
+		if err := snap.DataTo(&dst[i]); err != nil {
+			errs[i] = errors.WithStack(err)
+		}
+	}
+	return errs
+}
+
+func (s *firestoreStorage) Put(ctx context.Context, key Key, src interface{}) error {
+	ref, err := s.docRef(ctx, key)
+	if err != nil {
+		return err
+	}
+	_, err = ref.Set(ctx, src)
+	return errors.WithStack(err)
+}
+
+func (s *firestoreStorage) Delete(ctx context.Context, key Key) error {
+	ref, err := s.docRef(ctx, key)
+	if err != nil {
+		return err
+	}
+	_, err = ref.Delete(ctx)
+	return errors.WithStack(err)
+}
+
+// Stale runs a collection group query across every source's "Story"
+// subcollection. This requires a composite index on lastSave to exist:
+//
+//	gcloud firestore indexes composite create --collection-group=Story \
+//	  --field-config field-path=lastSave,order=ascending
+func (s *firestoreStorage) Stale(ctx context.Context, ttl time.Duration) ([]Key, []Item, error) {
+	db, err := s.db(ctx)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	cutoff := time.Now().Add(-ttl)
+	iter := db.CollectionGroup("Story").Where("lastSave", "<", cutoff).Documents(ctx)
+	defer iter.Stop()
+
+	var keys []Key
+	var items []Item
+	for {
+		snap, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+
+		var item Item
+		if err := snap.DataTo(&item); err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+
+		// snap.Ref is .../<source>/Root/Story/<id>: the source name is the
+		// ID of the collection two levels above this document.
+		keys = append(keys, Key{
+			Kind:   "Story",
+			ID:     item.ID,
+			Parent: &Key{Kind: snap.Ref.Parent.Parent.Parent.ID, Name: "Root"},
+		})
+		items = append(items, item)
+	}
+	return keys, items, nil
+}
+
+// Search runs an array-contains query for tokens[0], the only kind of
+// array-membership filter Firestore allows per query, then filters the
+// results down to items whose Tokens also contains every remaining token.
+// This requires a composite index on Tokens (array-contains) + LastSave if
+// combined with ordering elsewhere, but none is needed for this query alone.
+func (s *firestoreStorage) Search(ctx context.Context, tokens []string) ([]Item, error) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	db, err := s.db(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	iter := db.CollectionGroup("Story").Where("tokens", "array-contains", tokens[0]).Documents(ctx)
+	defer iter.Stop()
+
+	var items []Item
+	for {
+		snap, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		var item Item
+		if err := snap.DataTo(&item); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// snap.Ref is .../<source>/Root/Story/<id>, same layout Stale reads
+		// the source name out of.
+		item.Source = snap.Ref.Parent.Parent.Parent.ID
+		if containsAll(item.Tokens, tokens[1:]) {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
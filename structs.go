@@ -10,10 +10,11 @@ var ErrIgnoredItem = errors.New("item ignored")
 
 // SendMessageRequest is a struct that maps to a sendMessage request.
 type SendMessageRequest struct {
-	ChatID      string               `json:"chat_id"`
-	Text        string               `json:"text"`
-	ParseMode   string               `json:"parse_mode,omitempty"`
-	ReplyMarkup InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+	ChatID           string               `json:"chat_id"`
+	Text             string               `json:"text"`
+	ParseMode        string               `json:"parse_mode,omitempty"`
+	ReplyMarkup      InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+	ReplyToMessageID int64                `json:"reply_to_message_id,omitempty"`
 }
 
 // InlineKeyboardMarkup type.
@@ -60,6 +61,22 @@ type DeleteMessageResponse struct {
 	Description string `json:"description"`
 }
 
+// TelegramResponse is the envelope every Telegram Bot API response shares.
+// doTelegram decodes into it first to check for a retryable failure before
+// decoding the method-specific response.
+type TelegramResponse struct {
+	OK          bool                `json:"ok"`
+	ErrorCode   int64               `json:"error_code,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Parameters  *ResponseParameters `json:"parameters,omitempty"`
+}
+
+// ResponseParameters carries extra machine-readable data Telegram attaches
+// to some error responses, e.g. retry_after on HTTP 429.
+type ResponseParameters struct {
+	RetryAfter int64 `json:"retry_after,omitempty"`
+}
+
 // ShouldIgnoreError return true if the message contains an error but should be ignored.
 func (r *DeleteMessageResponse) ShouldIgnoreError() bool {
 	return (r.ErrorCode == 400 &&
@@ -71,3 +88,71 @@ func (r *DeleteMessageResponse) ShouldIgnoreError() bool {
 			// channel forever.
 			strings.Contains(r.Description, "message can't be deleted")))
 }
+
+// GetUpdatesRequest is the request to the getUpdates method.
+type GetUpdatesRequest struct {
+	Offset  int64 `json:"offset,omitempty"`
+	Timeout int64 `json:"timeout,omitempty"`
+}
+
+// GetUpdatesResponse is the response from the getUpdates method.
+type GetUpdatesResponse struct {
+	OK     bool     `json:"ok"`
+	Result []Update `json:"result"`
+}
+
+// Update is a single inbound update from Telegram, as delivered by
+// getUpdates or a webhook call. Only the fields this bot currently acts on
+// are populated; the rest of Telegram's envelope is ignored.
+type Update struct {
+	UpdateID      int64          `json:"update_id"`
+	Message       *Message       `json:"message,omitempty"`
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+}
+
+// Message is a Telegram message, as found on Update.Message.
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	Chat      Chat   `json:"chat"`
+	From      User   `json:"from"`
+	Text      string `json:"text"`
+}
+
+// Command returns the leading "/command" token of the message text, with
+// any "@botname" suffix stripped, or "" if the message isn't a command.
+func (m *Message) Command() string {
+	if m == nil || !strings.HasPrefix(m.Text, "/") {
+		return ""
+	}
+	cmd := strings.SplitN(m.Text, " ", 2)[0]
+	return strings.SplitN(cmd, "@", 2)[0]
+}
+
+// Args returns the text of the message following the leading "/command"
+// token, or "" if there is none.
+func (m *Message) Args() string {
+	parts := strings.SplitN(m.Text, " ", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
+
+// CallbackQuery is sent when a user taps an inline keyboard button.
+type CallbackQuery struct {
+	ID      string  `json:"id"`
+	From    User    `json:"from"`
+	Message Message `json:"message"`
+	Data    string  `json:"data"`
+}
+
+// Chat identifies where a Message was sent.
+type Chat struct {
+	ID int64 `json:"id"`
+}
+
+// User identifies who sent a Message.
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
@@ -0,0 +1,55 @@
+package bots
+
+import "testing"
+
+func TestMessageCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain command", "/top", "/top"},
+		{"command with args", "/search foo bar", "/search"},
+		{"command with botname suffix", "/top@yahnc_bot", "/top"},
+		{"command with botname suffix and args", "/search@yahnc_bot foo", "/search"},
+		{"not a command", "just some text", ""},
+		{"empty text", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Message{Text: tt.text}
+			if got := m.Command(); got != tt.want {
+				t.Errorf("Command() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("nil message", func(t *testing.T) {
+		var m *Message
+		if got := m.Command(); got != "" {
+			t.Errorf("Command() = %q, want \"\"", got)
+		}
+	})
+}
+
+func TestMessageArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"no args", "/top", ""},
+		{"single arg", "/search foo", "foo"},
+		{"multiple words", "/search foo bar", "foo bar"},
+		{"extra whitespace", "/search   foo bar  ", "foo bar"},
+		{"no command at all", "hello world", "world"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Message{Text: tt.text}
+			if got := m.Args(); got != tt.want {
+				t.Errorf("Args() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package bots
+
+import (
+	"context"
+	"time"
+)
+
+// TaskQueue schedules background work that shouldn't block the HTTP request
+// that triggered it: editing or sending a Telegram message, or deleting one.
+// On App Engine this is backed by the task queue via delay.Func; on Cloud
+// Run it's backed by Cloud Tasks posting back to this service's own /tasks
+// endpoint, since Cloud Tasks has no equivalent of a Go closure to enqueue.
+// Every method takes a delay, honored via each backend's own scheduling
+// primitive (App Engine's Task.Delay, Cloud Tasks' Task.ScheduleTime)
+// instead of the caller blocking on it: retryBackoff's callers use this to
+// schedule a Telegram retry without tying up a live request for the
+// duration of the backoff.
+type TaskQueue interface {
+	// EnqueueEdit schedules an edit of the message for source's item id,
+	// previously saved as prev, now at the given rank, to run after delay.
+	// attempt is the retry count to pass through to retryBackoff. notifyOnly
+	// skips straight to the threaded transition reply, for a retry that
+	// follows the text edit having already succeeded.
+	EnqueueEdit(ctx context.Context, source string, id int64, rank int, prev Item, notifyOnly bool, attempt int, delay time.Duration) error
+	// EnqueueSend schedules sending a new message for source's item id at
+	// the given rank, to run after delay.
+	EnqueueSend(ctx context.Context, source string, id int64, rank int, attempt int, delay time.Duration) error
+	// EnqueueDelete schedules deleting source's messageID for item id, to
+	// run after delay.
+	EnqueueDelete(ctx context.Context, source string, id, messageID int64, attempt int, delay time.Duration) error
+}
+
+// taskQueue is the TaskQueue implementation in effect for this process.
+var taskQueue = newTaskQueue()
+
+func newTaskQueue() TaskQueue {
+	if onCloudRun() {
+		return &cloudTasksQueue{}
+	}
+	return appengineTaskQueue{}
+}
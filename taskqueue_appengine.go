@@ -0,0 +1,44 @@
+package bots
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/appengine/delay"
+	"google.golang.org/appengine/taskqueue"
+)
+
+// appengineTaskQueue is the TaskQueue implementation used on classic App
+// Engine, backed directly by delay.Func.
+type appengineTaskQueue struct{}
+
+var editMessageFunc = delay.Func("editMessage", runEditMessage)
+var sendMessageFunc = delay.Func("sendMessage", runSendMessage)
+var deleteMessageFunc = delay.Func("deleteMessage", runDeleteMessage)
+
+// enqueueDelayed builds fn's task via delay.Func.Task instead of
+// delay.Func.Call so delay can be set on it before it's added to the
+// default queue, the same scheduled-retry behavior cloudTasksQueue gets
+// from Cloud Tasks' Task.ScheduleTime.
+func enqueueDelayed(ctx context.Context, fn *delay.Func, delay time.Duration, args ...interface{}) error {
+	task, err := fn.Task(args...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	task.Delay = delay
+	_, err = taskqueue.Add(ctx, task, "")
+	return errors.WithStack(err)
+}
+
+func (appengineTaskQueue) EnqueueEdit(ctx context.Context, source string, id int64, rank int, prev Item, notifyOnly bool, attempt int, delay time.Duration) error {
+	return enqueueDelayed(ctx, editMessageFunc, delay, source, id, rank, prev, notifyOnly, attempt)
+}
+
+func (appengineTaskQueue) EnqueueSend(ctx context.Context, source string, id int64, rank int, attempt int, delay time.Duration) error {
+	return enqueueDelayed(ctx, sendMessageFunc, delay, source, id, rank, attempt)
+}
+
+func (appengineTaskQueue) EnqueueDelete(ctx context.Context, source string, id, messageID int64, attempt int, delay time.Duration) error {
+	return enqueueDelayed(ctx, deleteMessageFunc, delay, source, id, messageID, attempt)
+}
@@ -0,0 +1,192 @@
+package bots
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	cloudtasks "cloud.google.com/go/cloudtasks/apiv2"
+	"github.com/pkg/errors"
+	"google.golang.org/api/idtoken"
+	taskspb "google.golang.org/genproto/googleapis/cloud/tasks/v2"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Environment variables cloudTasksQueue reads to address its Cloud Tasks
+// queue and the Cloud Run service /tasks should call back into, and to
+// authenticate that callback.
+const (
+	cloudTasksLocationEnv       = "CLOUD_TASKS_LOCATION"
+	cloudTasksQueueEnv          = "CLOUD_TASKS_QUEUE"
+	cloudRunServiceURLEnv       = "SERVICE_URL"
+	cloudTasksServiceAccountEnv = "CLOUD_TASKS_SERVICE_ACCOUNT"
+)
+
+// taskKind names the task bodies tasksHandler can run.
+type taskKind string
+
+const (
+	taskEdit   taskKind = "edit"
+	taskSend   taskKind = "send"
+	taskDelete taskKind = "delete"
+)
+
+// taskPayload is the JSON body cloudTasksQueue posts to /tasks and
+// tasksHandler decodes. One shape is shared by all three task kinds so the
+// dispatcher only needs a single endpoint.
+type taskPayload struct {
+	Task       taskKind `json:"task"`
+	Source     string   `json:"source"`
+	ID         int64    `json:"id"`
+	Rank       int      `json:"rank"`
+	Prev       *Item    `json:"prev,omitempty"`
+	MessageID  int64    `json:"message_id,omitempty"`
+	NotifyOnly bool     `json:"notify_only,omitempty"`
+	Attempt    int      `json:"attempt"`
+}
+
+// cloudTasksQueue is the TaskQueue implementation used on Cloud Run: it
+// enqueues an HTTP task that Cloud Tasks later delivers back to this same
+// service's /tasks endpoint, which tasksHandler dispatches.
+type cloudTasksQueue struct {
+	once   sync.Once
+	client *cloudtasks.Client
+	err    error
+}
+
+func (q *cloudTasksQueue) cli(ctx context.Context) (*cloudtasks.Client, error) {
+	q.once.Do(func() {
+		q.client, q.err = cloudtasks.NewClient(ctx)
+	})
+	return q.client, q.err
+}
+
+// queuePath is the Cloud Tasks queue resource name tasks are enqueued onto.
+func queuePath() string {
+	return fmt.Sprintf("projects/%s/locations/%s/queues/%s",
+		os.Getenv(firestoreProjectEnv), os.Getenv(cloudTasksLocationEnv), os.Getenv(cloudTasksQueueEnv))
+}
+
+func (q *cloudTasksQueue) enqueue(ctx context.Context, payload taskPayload, delay time.Duration) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	cli, err := q.cli(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	task := &taskspb.Task{
+		MessageType: &taskspb.Task_HttpRequest{
+			HttpRequest: &taskspb.HttpRequest{
+				HttpMethod: taskspb.HttpMethod_POST,
+				Url:        os.Getenv(cloudRunServiceURLEnv) + "/tasks",
+				Headers:    map[string]string{"Content-Type": "application/json"},
+				Body:       body,
+				// Cloud Tasks mints a Google-signed OIDC token for this
+				// service account and attaches it as the Authorization
+				// header; verifyTaskRequest checks for the same token
+				// before tasksHandler dispatches the payload. /tasks
+				// would otherwise sit unauthenticated next to the public
+				// /webhook endpoint, letting anyone on the internet
+				// trigger arbitrary send/edit/delete calls.
+				AuthorizationHeader: &taskspb.HttpRequest_OidcToken{
+					OidcToken: &taskspb.OidcToken{
+						ServiceAccountEmail: os.Getenv(cloudTasksServiceAccountEnv),
+						Audience:            os.Getenv(cloudRunServiceURLEnv),
+					},
+				},
+			},
+		},
+	}
+	// ScheduleTime is how Cloud Tasks supports a delayed retry without the
+	// caller blocking on it, the same role App Engine's Task.Delay plays for
+	// appengineTaskQueue. Leaving it unset (delay == 0) schedules the task
+	// for "now", same as before this param existed.
+	if delay > 0 {
+		task.ScheduleTime = timestamppb.New(time.Now().Add(delay))
+	}
+
+	_, err = cli.CreateTask(ctx, &taskspb.CreateTaskRequest{
+		Parent: queuePath(),
+		Task:   task,
+	})
+	return errors.WithStack(err)
+}
+
+func (q *cloudTasksQueue) EnqueueEdit(ctx context.Context, source string, id int64, rank int, prev Item, notifyOnly bool, attempt int, delay time.Duration) error {
+	return q.enqueue(ctx, taskPayload{Task: taskEdit, Source: source, ID: id, Rank: rank, Prev: &prev, NotifyOnly: notifyOnly, Attempt: attempt}, delay)
+}
+
+func (q *cloudTasksQueue) EnqueueSend(ctx context.Context, source string, id int64, rank int, attempt int, delay time.Duration) error {
+	return q.enqueue(ctx, taskPayload{Task: taskSend, Source: source, ID: id, Rank: rank, Attempt: attempt}, delay)
+}
+
+func (q *cloudTasksQueue) EnqueueDelete(ctx context.Context, source string, id, messageID int64, attempt int, delay time.Duration) error {
+	return q.enqueue(ctx, taskPayload{Task: taskDelete, Source: source, ID: id, MessageID: messageID, Attempt: attempt}, delay)
+}
+
+// verifyTaskRequest checks that r carries the OIDC token enqueue asked
+// Cloud Tasks to attach: a Google-signed token for cloudTasksServiceAccountEnv
+// with this service's own URL as audience. This is the only thing standing
+// between /tasks and an unauthenticated POST, since /webhook must stay
+// public for Telegram to reach it on the same service.
+func verifyTaskRequest(ctx context.Context, r *http.Request) error {
+	auth := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if token == "" || token == auth {
+		return errors.New("missing bearer token")
+	}
+
+	payload, err := idtoken.Validate(ctx, token, os.Getenv(cloudRunServiceURLEnv))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if email, _ := payload.Claims["email"].(string); email != os.Getenv(cloudTasksServiceAccountEnv) {
+		return errors.Errorf("token was issued for unexpected service account %q", email)
+	}
+	return nil
+}
+
+// tasksHandler is the Cloud Tasks delivery target registered at /tasks: it
+// decodes a taskPayload and runs the task body the payload names.
+func tasksHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := RequestContext(r)
+	defer cancel()
+
+	if err := verifyTaskRequest(ctx, r); err != nil {
+		logger.Errorf(ctx, "rejecting unauthenticated /tasks request: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var payload taskPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		logger.Errorf(ctx, "got error decoding task payload: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch payload.Task {
+	case taskEdit:
+		var prev Item
+		if payload.Prev != nil {
+			prev = *payload.Prev
+		}
+		runEditMessage(ctx, payload.Source, payload.ID, payload.Rank, prev, payload.NotifyOnly, payload.Attempt)
+	case taskSend:
+		runSendMessage(ctx, payload.Source, payload.ID, payload.Rank, payload.Attempt)
+	case taskDelete:
+		runDeleteMessage(ctx, payload.Source, payload.ID, payload.MessageID, payload.Attempt)
+	default:
+		logger.Errorf(ctx, "got unknown task kind: %q", payload.Task)
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,99 @@
+package bots
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RequestTimeout is the deadline RequestContext puts on each incoming
+// request's context, on App Engine and Cloud Run alike, shorter than DefaultTimeout so fanned-out goroutines notice the
+// request is being torn down and stop instead of leaking, the same way
+// net.Conn deadlines bound a read/write that would otherwise block forever.
+const RequestTimeout = 8 * time.Minute
+
+// maxRetryBackoff caps how long doTelegram will ever ask a caller to wait,
+// regardless of what Telegram's retry_after says.
+const maxRetryBackoff = 2 * time.Minute
+
+// defaultRetryAfterSeconds is used when Telegram returns a retryable status
+// without a parameters.retry_after, which happens on some 5xx responses.
+const defaultRetryAfterSeconds = 5
+
+// ErrTelegramRetryable is returned by doTelegram when the request failed
+// with HTTP 429 or 5xx, which Telegram expects callers to retry after
+// RetryAfter.
+type ErrTelegramRetryable struct {
+	Response   TelegramResponse
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *ErrTelegramRetryable) Error() string {
+	return fmt.Sprintf("telegram: retryable error, retry after %s: %#v", e.RetryAfter, e.Response)
+}
+
+// doTelegram posts req as JSON to method. If Telegram responds with HTTP 429
+// or 5xx, it returns an *ErrTelegramRetryable carrying a capped, jittered
+// backoff derived from the response's retry_after instead of treating the
+// failure as terminal. For any other status it decodes the response body
+// into resp (if non-nil) and returns nil, whether or not the response's ok
+// field is true; checking it is left to the caller.
+func doTelegram(ctx context.Context, method string, req, resp interface{}) error {
+	jsonBytes, err := json.Marshal(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	httpResp, err := httpClientFor(ctx).Post(TelegramAPI(method), "application/json", bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer httpResp.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var envelope TelegramResponse
+	if err := json.Unmarshal(bodyBytes, &envelope); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if httpResp.StatusCode == http.StatusTooManyRequests || httpResp.StatusCode >= http.StatusInternalServerError {
+		return &ErrTelegramRetryable{Response: envelope, RetryAfter: retryAfterBackoff(envelope)}
+	}
+
+	if resp == nil {
+		return nil
+	}
+	if err := json.Unmarshal(bodyBytes, resp); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// retryAfterBackoff turns envelope's retry_after (seconds) into a capped,
+// jittered backoff duration, falling back to defaultRetryAfterSeconds when
+// Telegram didn't send one.
+func retryAfterBackoff(envelope TelegramResponse) time.Duration {
+	seconds := int64(defaultRetryAfterSeconds)
+	if envelope.Parameters != nil && envelope.Parameters.RetryAfter > 0 {
+		seconds = envelope.Parameters.RetryAfter
+	}
+
+	backoff := time.Duration(seconds) * time.Second
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(backoff)/4 + 1))
+	return backoff + jitter
+}
@@ -0,0 +1,53 @@
+package bots
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryAfterBackoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		envelope   TelegramResponse
+		wantMin    int64 // seconds, base backoff before jitter
+		wantMaxCap int64 // seconds, base backoff capped at maxRetryBackoff; its jitter ceiling is added below
+	}{
+		{
+			name:       "no parameters falls back to default",
+			envelope:   TelegramResponse{},
+			wantMin:    defaultRetryAfterSeconds,
+			wantMaxCap: defaultRetryAfterSeconds,
+		},
+		{
+			name:       "uses retry_after from parameters",
+			envelope:   TelegramResponse{Parameters: &ResponseParameters{RetryAfter: 10}},
+			wantMin:    10,
+			wantMaxCap: 10,
+		},
+		{
+			name:       "zero retry_after falls back to default",
+			envelope:   TelegramResponse{Parameters: &ResponseParameters{RetryAfter: 0}},
+			wantMin:    defaultRetryAfterSeconds,
+			wantMaxCap: defaultRetryAfterSeconds,
+		},
+		{
+			name:       "caps at maxRetryBackoff",
+			envelope:   TelegramResponse{Parameters: &ResponseParameters{RetryAfter: 3600}},
+			wantMin:    int64(maxRetryBackoff.Seconds()),
+			wantMaxCap: int64(maxRetryBackoff.Seconds()),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxJitter := tt.wantMaxCap/4 + 1
+			min := time.Duration(tt.wantMin) * time.Second
+			max := time.Duration(tt.wantMaxCap+maxJitter) * time.Second
+			for i := 0; i < 20; i++ {
+				got := retryAfterBackoff(tt.envelope)
+				if got < min || got > max {
+					t.Fatalf("retryAfterBackoff(%+v) = %s, want within [%s, %s]", tt.envelope, got, min, max)
+				}
+			}
+		})
+	}
+}